@@ -0,0 +1,27 @@
+package randmap
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// readScalar reads the value at ptr as a t, for the float kinds, where we
+// can safely read it as a fixed-size value ourselves instead of paying for
+// a reflect.NewAt/Elem/Interface round trip. It handles float32/float64
+// only: a map[float64]V can hold many distinct NaN keys (the
+// runtime gives every NaN a fresh, randomized hash, so they land in
+// different cells despite being indistinguishable by ==), and copying the
+// raw 4/8 bytes straight out of the bucket preserves each NaN's exact bit
+// pattern just as well as reflect does, without the reflect overhead.
+// readScalar reports false for any other kind, leaving the caller to fall
+// back to reflect.
+func readScalar(t reflect.Type, ptr unsafe.Pointer) (v interface{}, ok bool) {
+	switch t.Kind() {
+	case reflect.Float64:
+		return *(*float64)(ptr), true
+	case reflect.Float32:
+		return *(*float32)(ptr), true
+	default:
+		return nil, false
+	}
+}
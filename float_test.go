@@ -0,0 +1,126 @@
+package randmap
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// TestNanKeys mirrors the runtime's own TestNan: a map[float64]V can
+// contain many NaN keys that are indistinguishable by == (and so can't be
+// told apart by the caller), because the runtime assigns each one a fresh,
+// randomized hash and stores it in whatever cell that hash lands in.
+func TestNanKeys(t *testing.T) {
+	const nans = 10
+	const iters = 50000
+	m := make(map[float64]int, nans)
+	for i := 0; i < nans; i++ {
+		m[math.NaN()] = i
+	}
+	if len(m) != nans {
+		t.Fatalf("expected %v distinct NaN entries, got %v", nans, len(m))
+	}
+
+	counts := make([]int, nans)
+	for i := 0; i < iters; i++ {
+		v := FastVal(m).(int)
+		counts[v]++
+	}
+
+	want := iters / nans
+	for n, c := range counts {
+		if want/2 > c || c > want*2 {
+			t.Errorf("suspicious count for NaN entry %v: expected ~%v, got %v", n, want, c)
+		}
+	}
+}
+
+// TestNegativeZeroKey checks that 0.0 and -0.0, which compare equal but
+// have distinct bit patterns, collide into a single map entry, and that
+// FastKey always returns a key equal to 0 for it. The sign of the
+// returned zero is not checked.
+func TestNegativeZeroKey(t *testing.T) {
+	m := map[float64]int{
+		0.0: 1,
+		math.Copysign(0, -1): 2,
+	}
+	if len(m) != 1 {
+		t.Fatalf("expected 0.0 and -0.0 to collide in the map, got %v entries", len(m))
+	}
+
+	for i := 0; i < 1000; i++ {
+		k := FastKey(m).(float64)
+		if k != 0 {
+			t.Fatalf("expected key 0, got %v", k)
+		}
+	}
+}
+
+func TestFastKeyFloat(t *testing.T) {
+	const iters = 100000
+	m := map[float64]int{
+		0.5: 0, 1.5: 1, 2.5: 2, 3.5: 3, 4.5: 4,
+	}
+	counts := make(map[float64]int)
+	for i := 0; i < iters; i++ {
+		counts[FastKey(m).(float64)]++
+	}
+	if len(counts) != len(m) {
+		t.Fatalf("expected %v distinct keys, saw %v", len(m), len(counts))
+	}
+	want := iters / len(m)
+	for k, c := range counts {
+		if want/2 > c || c > want*2 {
+			t.Errorf("suspicious count for key %v: expected ~%v, got %v", k, want, c)
+		}
+	}
+}
+
+// TestFastKeyFloatDuringGrow exercises the float fast path against a
+// map large enough to span many buckets and overflow chains, with an
+// incremental grow still in progress (see TestEntropy for why 417
+// entries guarantees that) - TestFastKeyFloat above fits in a single
+// bucket and TestNanKeys in two, with no grow in progress, where a
+// bucket-occupancy bias in pickCell (or old/new bucket double-counting
+// mid-grow) would go unnoticed.
+func TestFastKeyFloatDuringGrow(t *testing.T) {
+	const n = 417
+	const iters = 50000
+	m := make(map[float64]int)
+	for i := 0; i < n; i++ {
+		m[float64(i)] = i
+	}
+
+	counts := make([]int, n)
+	for i := 0; i < iters; i++ {
+		counts[int(FastKey(m).(float64))]++
+	}
+
+	want := iters / n
+	for i, c := range counts {
+		if want/2 > c || c > want*2 {
+			t.Errorf("suspicious count for key %v: expected ~%v, got %v", i, want, c)
+		}
+	}
+}
+
+func TestReadScalarFloat(t *testing.T) {
+	f64 := 3.25
+	v, ok := readScalar(reflect.TypeOf(f64), unsafe.Pointer(&f64))
+	if !ok || v.(float64) != f64 {
+		t.Fatalf("readScalar(float64) = %v, %v; want %v, true", v, ok, f64)
+	}
+
+	f32 := float32(1.5)
+	v, ok = readScalar(reflect.TypeOf(f32), unsafe.Pointer(&f32))
+	if !ok || v.(float32) != f32 {
+		t.Fatalf("readScalar(float32) = %v, %v; want %v, true", v, ok, f32)
+	}
+
+	n := 7
+	_, ok = readScalar(reflect.TypeOf(n), unsafe.Pointer(&n))
+	if ok {
+		t.Fatalf("readScalar(int) reported ok, want fallback to reflect")
+	}
+}
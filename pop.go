@@ -0,0 +1,83 @@
+package randmap
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// PopKey selects a uniformly random key from m using the same bucket walk
+// as FastKey, deletes it from m, and returns it. It panics if m is empty.
+//
+// Because the cell address is already known from the random walk, this
+// avoids the second hash-and-lookup that `k := FastKey(m); delete(m, k)`
+// would pay. If the chosen logical bucket has already been evacuated by an
+// in-progress incremental grow (see TestInsert), PopKey falls through to
+// the corresponding new bucket, exactly as resolveBucket already does for
+// reads, and deletes there instead.
+func PopKey(m interface{}) interface{} {
+	k, _ := std.PopPair(m)
+	return k
+}
+
+// PopVal is like PopKey, but returns the value instead of the key.
+func PopVal(m interface{}) interface{} {
+	_, v := std.PopPair(m)
+	return v
+}
+
+// PopKey selects a uniformly random key from m, deletes it, and returns
+// it, using r as the source of randomness.
+func (r *Rand) PopKey(m interface{}) interface{} {
+	k, _ := r.PopPair(m)
+	return k
+}
+
+// PopVal is like PopKey, but returns the value instead of the key.
+func (r *Rand) PopVal(m interface{}) interface{} {
+	_, v := r.PopPair(m)
+	return v
+}
+
+// PopPair selects a uniformly random key/value pair out of m, deletes it,
+// and returns it, using r as the source of randomness. PopKey and PopVal
+// are thin wrappers around std.PopPair.
+func (r *Rand) PopPair(m interface{}) (key, val interface{}) {
+	h, keyType, elemType := mapData(m)
+	if h == nil || h.count == 0 {
+		panic("randmap: empty map")
+	}
+	l := newLayout(keyType, elemType)
+
+	b, j := r.pickCell(h, l)
+	kv, vv := readCell(keyType, elemType, l, b, j)
+	deleteCell(h, keyType, elemType, l, b, j)
+	return kv, vv
+}
+
+// deleteCell clears the key and elem stored at cell (b, j) and marks it
+// empty, without recomputing the key's hash or re-walking the bucket
+// array to find it.
+//
+// The clear goes through reflect.Value.Set rather than a raw byte-by-byte
+// memclr: if K or V contains a pointer (a string, slice, map, chan,
+// interface, pointer, or a struct embedding one), a raw clear never passes
+// through the compiler's write barrier, which can corrupt the heap under
+// concurrent GC marking. reflect.Value.Set uses the same barrier-protected
+// typedmemmove the runtime's own mapdelete relies on.
+func deleteCell(h *hmap, keyType, elemType reflect.Type, l layout, b unsafe.Pointer, j uintptr) {
+	reflect.NewAt(keyType, l.key(b, j)).Elem().Set(reflect.Zero(keyType))
+	reflect.NewAt(elemType, l.elem(b, j)).Elem().Set(reflect.Zero(elemType))
+	clearTophash(h, b, j)
+}
+
+// clearTophash marks cell (b, j) empty and shrinks h's count, once its key
+// and elem have already been cleared.
+func clearTophash(h *hmap, b unsafe.Pointer, j uintptr) {
+	// emptyOne is conservative: it doesn't let later lookups short-circuit
+	// past this cell the way emptyRest would, but determining that every
+	// following cell in the bucket is also empty (the precondition for
+	// using emptyRest) would cost the very lookup this function exists to
+	// avoid.
+	(*bmap)(b).tophash[j] = emptyOne
+	h.count--
+}
@@ -0,0 +1,120 @@
+package randmap
+
+import "testing"
+
+func TestPopKey(t *testing.T) {
+	m := map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 4: 4}
+	want := len(m)
+	for len(m) > 0 {
+		k := PopKey(m).(int)
+		if _, ok := m[k]; ok {
+			t.Fatalf("PopKey returned %v, but it is still in the map", k)
+		}
+		want--
+		if len(m) != want {
+			t.Fatalf("expected map length %v, got %v", want, len(m))
+		}
+	}
+}
+
+func TestPopVal(t *testing.T) {
+	m := map[int]int{0: 10, 1: 11, 2: 12}
+	seen := make(map[int]bool)
+	for len(m) > 0 {
+		v := PopVal(m).(int)
+		if seen[v] {
+			t.Fatalf("value %v popped twice", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected 3 distinct values, got %v", len(seen))
+	}
+}
+
+func TestPopKeyEmpty(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic when popping from an empty map")
+		}
+	}()
+	_ = PopKey(make(map[int]int))
+}
+
+func TestPopValPointerContaining(t *testing.T) {
+	// string and *int values contain pointers; popping them must clear
+	// the cell through a write-barrier-safe typed assignment rather than
+	// a raw byte clear, or the GC's tri-color invariant can be violated
+	// during concurrent marking.
+	m := map[int]string{0: "zero", 1: "one", 2: "two"}
+	seen := make(map[string]bool)
+	for len(m) > 0 {
+		v := PopVal(m).(string)
+		if seen[v] {
+			t.Fatalf("value %q popped twice", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected 3 distinct values, got %v", len(seen))
+	}
+
+	n := 5
+	pm := map[string]*int{"a": &n}
+	k := PopKey(pm).(string)
+	if k != "a" {
+		t.Fatalf("expected key %q, got %q", "a", k)
+	}
+	if len(pm) != 0 {
+		t.Fatalf("expected empty map after popping only entry, got %v", pm)
+	}
+}
+
+func TestPopKeyDuringGrow(t *testing.T) {
+	// Mirrors TestInsert: PopKey must work correctly mid-incremental-grow,
+	// falling through to the new bucket if the old one was evacuated.
+	// The 27th insert starts a grow from 4 to 8 buckets and evacuates at
+	// most 2 of the 4 old ones; PopKey does not advance the grow, so every
+	// pop below runs with it still in progress.
+	const outer = 27
+	m := make(map[int]int)
+	for i := 0; i < outer; i++ {
+		m[i] = i
+	}
+	for len(m) > 0 {
+		before := len(m)
+		k := PopKey(m).(int)
+		if _, ok := m[k]; ok {
+			t.Fatalf("PopKey returned %v, but it is still in the map", k)
+		}
+		if len(m) != before-1 {
+			t.Fatalf("expected map to shrink by 1, got %v -> %v", before, len(m))
+		}
+	}
+}
+
+func TestPopKeyDuringGrowDistribution(t *testing.T) {
+	// Presence/absence (TestPopKeyDuringGrow) can't catch a pickCell that
+	// is internally biased toward some cells over others - it still
+	// always returns a live key. Rebuild a map of each size up to a
+	// point that's sure to straddle an incremental grow (see
+	// TestEntropy), and tally which key a single PopKey removes across
+	// many independent trials at that size.
+	const outer = 50
+	const inner = 2000
+	for n := 1; n <= outer; n++ {
+		counts := make([]int, n)
+		for i := 0; i < inner; i++ {
+			m := make(map[int]int)
+			for k := 0; k < n; k++ {
+				m[k] = k
+			}
+			counts[PopKey(m).(int)]++
+		}
+		for k, c := range counts {
+			if (inner/n)/2 > c || c > (inner/n)*2 {
+				t.Errorf("n=%v: suspicious count for key %v: expected %v-%v, got %v", n, k, (inner/n)/2, (inner/n)*2, c)
+			}
+		}
+	}
+}
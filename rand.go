@@ -0,0 +1,59 @@
+package randmap
+
+import "math/rand"
+
+// Rand is a pluggable source of randomness for the selection functions in
+// this package. Iter and FastIter take a Rand but do not draw from it
+// (their order is the Go runtime's range order), and the generic functions
+// in typed.go (KeyOf, ValOf, PopKeyOf, ...) always draw from std. The zero
+// value of Rand (and the package-level std used by
+// the top-level Key/Val/FastKey/... functions) falls back to the global
+// math/rand functions, which serialize on math/rand's global lock — see
+// TestConcurrent. Construct a Rand with NewRand to use an independent
+// *rand.Rand instead, e.g. one per goroutine seeded with rand.NewSource,
+// for reproducible output (useful for regression tests like TestGhostIndex
+// and TestEntropy) or for lock-free parallel sampling.
+type Rand struct {
+	r *rand.Rand
+}
+
+// std is the package-level default Rand that Key, Val, FastKey, FastVal,
+// SampleN, WeightedSampleN, PopKey, and PopVal wrap.
+var std = &Rand{}
+
+// NewRand returns a Rand that draws from src instead of the global
+// math/rand functions.
+func NewRand(src rand.Source) *Rand {
+	return &Rand{r: rand.New(src)}
+}
+
+func (r *Rand) intn(n int) int {
+	if r == nil || r.r == nil {
+		return rand.Intn(n)
+	}
+	return r.r.Intn(n)
+}
+
+func (r *Rand) int63n(n int64) int64 {
+	if r == nil || r.r == nil {
+		return rand.Int63n(n)
+	}
+	return r.r.Int63n(n)
+}
+
+func (r *Rand) float64() float64 {
+	if r == nil || r.r == nil {
+		return rand.Float64()
+	}
+	return r.r.Float64()
+}
+
+// shuffle randomizes the order of p in place using the Fisher-Yates
+// shuffle. Used by SyncMap.Iter, which has no cheaper way to randomize
+// order than snapshotting and shuffling.
+func (r *Rand) shuffle(p []Pair) {
+	for i := len(p) - 1; i > 0; i-- {
+		j := r.intn(i + 1)
+		p[i], p[j] = p[j], p[i]
+	}
+}
@@ -0,0 +1,37 @@
+package randmap
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRandDeterministic(t *testing.T) {
+	m := map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 4: 4, 5: 5, 6: 6, 7: 7, 8: 8, 9: 9}
+
+	r1 := NewRand(rand.NewSource(42))
+	r2 := NewRand(rand.NewSource(42))
+
+	const iters = 1000
+	for i := 0; i < iters; i++ {
+		k1 := r1.FastKey(m)
+		k2 := r2.FastKey(m)
+		if k1 != k2 {
+			t.Fatalf("iteration %v: same seed produced different keys: %v != %v", i, k1, k2)
+		}
+	}
+}
+
+func TestRandIndependent(t *testing.T) {
+	m := map[int]int{0: 0, 1: 1, 2: 2}
+
+	r := NewRand(rand.NewSource(1))
+	if got := r.Key(m); got != 0 && got != 1 && got != 2 {
+		t.Fatalf("unexpected key: %v", got)
+	}
+	if got := r.SampleN(m, 2); len(got) != 2 {
+		t.Fatalf("expected 2 pairs, got %v", len(got))
+	}
+	if got := r.PopKey(m); len(m) != 2 {
+		t.Fatalf("expected map to shrink after PopKey, got %v (popped %v)", len(m), got)
+	}
+}
@@ -0,0 +1,423 @@
+// Package randmap selects uniformly random keys, values, and key/value
+// pairs out of a Go map by walking its bucket array directly instead of
+// going through the usual idiom of a randomly-offset range loop.
+//
+// A Go map is, under the hood, a hash table: a bucket array plus, during
+// growth, a second "old" bucket array that is incrementally evacuated into
+// the new one. `range` visits buckets in a randomized order already, but
+// the builtin stdlib provides no way to index directly into a bucket, or
+// to read a single key/elem without boxing it through an interface{} and
+// a type switch. randmap reaches into that bucket array itself, using the
+// `unsafe` package, and picks a uniformly random occupied cell directly by
+// reservoir sampling over every bucket (see pickCell) - one pass over the
+// bucket array, not the n hash computations and interface allocations a
+// loop of `for k := range m { ... }` would cost to gather the same
+// answer.
+//
+// This only works because the layout of runtime.hmap and runtime.bmap has
+// been effectively stable across Go releases; randmap is pinned to that
+// layout and will need updating if it ever changes.
+//
+// Every top-level function (Key, Val, FastKey, ...) is a thin wrapper
+// around the same method on a package-level default Rand, which in turn
+// falls back to the global math/rand functions. Construct a Rand with
+// NewRand for reproducible output or to avoid contending on math/rand's
+// global lock.
+package randmap
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// These constants mirror the ones in runtime/map.go. bucketCnt is the
+// number of key/elem slots in a single bucket.
+const (
+	bucketCntBits = 3
+	bucketCnt     = 1 << bucketCntBits
+
+	// tophash values. A real tophash stored for an occupied cell is never
+	// less than minTopHash; the values below it mark special states.
+	emptyRest      = 0 // cell is empty, and so are all cells after it in the bucket
+	emptyOne       = 1 // cell is empty
+	evacuatedX     = 2 // key/elem is valid, was evacuated to the first half of a larger table
+	evacuatedY     = 3 // same as evacuatedX, but to the second half
+	evacuatedEmpty = 4 // cell is empty, bucket is evacuated
+	minTopHash     = 5 // minimum tophash for an occupied cell
+)
+
+// hmap mirrors runtime.hmap. Field order and types must match exactly.
+type hmap struct {
+	count      int
+	flags      uint8
+	B          uint8
+	noverflow  uint16
+	hash0      uint32
+	buckets    unsafe.Pointer
+	oldbuckets unsafe.Pointer
+	nevacuate  uintptr
+	extra      *mapextra
+}
+
+// mapextra mirrors runtime.mapextra.
+type mapextra struct {
+	overflow    *[]*bmap
+	oldoverflow *[]*bmap
+	nextOverflow *bmap
+}
+
+// bmap mirrors the fixed-size header of runtime.bmap. The compiler-generated
+// real bucket type appends bucketCnt keys, then bucketCnt elems, then
+// (conditionally) an overflow pointer after this header; we compute those
+// offsets ourselves below since we don't have the compiler's per-map-type
+// layout available to us.
+type bmap struct {
+	tophash [bucketCnt]uint8
+}
+
+// layout describes the byte layout of one bucket for a given map's key and
+// elem types, computed once per call via reflection.
+type layout struct {
+	keySize    uintptr
+	elemSize   uintptr
+	bucketSize uintptr // size of one bmap, including trailing overflow pointer
+	keysOff    uintptr
+	elemsOff   uintptr
+	overflowOff uintptr
+}
+
+func newLayout(keyType, elemType reflect.Type) layout {
+	return newLayoutSizes(keyType.Size(), elemType.Size())
+}
+
+// newLayoutSizes is the reflect-free core of newLayout: given just the key
+// and elem sizes, it computes the rest of a bucket's layout. The generics
+// API in typed.go uses this directly, via unsafe.Sizeof on its type
+// parameters, to avoid newLayout's reflect.Type.Size call.
+func newLayoutSizes(keySize, elemSize uintptr) layout {
+	var l layout
+	l.keySize = keySize
+	l.elemSize = elemSize
+
+	l.keysOff = unsafe.Sizeof(bmap{})
+	l.elemsOff = l.keysOff + bucketCnt*l.keySize
+	l.overflowOff = l.elemsOff + bucketCnt*l.elemSize
+
+	// The overflow pointer must be pointer-aligned.
+	if rem := l.overflowOff % unsafe.Sizeof(uintptr(0)); rem != 0 {
+		l.overflowOff += unsafe.Sizeof(uintptr(0)) - rem
+	}
+	l.bucketSize = l.overflowOff + unsafe.Sizeof(uintptr(0))
+	return l
+}
+
+func (l layout) key(b unsafe.Pointer, i uintptr) unsafe.Pointer {
+	return unsafe.Pointer(uintptr(b) + l.keysOff + i*l.keySize)
+}
+
+func (l layout) elem(b unsafe.Pointer, i uintptr) unsafe.Pointer {
+	return unsafe.Pointer(uintptr(b) + l.elemsOff + i*l.elemSize)
+}
+
+func (l layout) overflow(b unsafe.Pointer) *bmap {
+	return *(**bmap)(unsafe.Pointer(uintptr(b) + l.overflowOff))
+}
+
+// mapData returns the hmap header backing m, along with the map's key and
+// elem reflect.Types.
+func mapData(m interface{}) (*hmap, reflect.Type, reflect.Type) {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		panic("randmap: argument is not a map")
+	}
+	h := (*hmap)(unsafe.Pointer(v.Pointer()))
+	t := v.Type()
+	return h, t.Key(), t.Elem()
+}
+
+// bucketCount returns the number of buckets currently live in h (1<<h.B).
+func bucketCount(h *hmap) uintptr {
+	return uintptr(1) << h.B
+}
+
+// bucketAt returns a pointer to bucket i of the given bucket array.
+func bucketAt(buckets unsafe.Pointer, l layout, i uintptr) unsafe.Pointer {
+	return unsafe.Pointer(uintptr(buckets) + i*l.bucketSize)
+}
+
+// resolveBucket returns the bucket that actually holds live data for logical
+// bucket index i, following the old-bucket evacuation chain used during
+// incremental map growth. If the old bucket at i has not yet been
+// evacuated, it is returned directly (along with oldGeneration=true); if it
+// has been evacuated, or there is no growth in progress, the current bucket
+// is returned.
+func resolveBucket(h *hmap, l layout, i uintptr) (b unsafe.Pointer, oldGeneration bool) {
+	if h.oldbuckets == nil {
+		return bucketAt(h.buckets, l, i), false
+	}
+
+	// The old table is half the size of the new one (or the new one was
+	// just allocated with the same size, for same-size growth); mask i
+	// down to an old-table index.
+	oldB := h.B - 1
+	mask := uintptr(1)<<oldB - 1
+	oldIdx := i & mask
+	oldB1 := bucketAt(h.oldbuckets, l, oldIdx)
+
+	if !evacuated(oldB1) {
+		return oldB1, true
+	}
+	return bucketAt(h.buckets, l, i), false
+}
+
+func evacuated(b unsafe.Pointer) bool {
+	h := (*bmap)(b).tophash[0]
+	return h > emptyOne && h < minTopHash
+}
+
+// Key returns a uniformly random key from m. It panics if m is empty or if
+// m is not a map. It is equivalent to std.Key(m); see Rand for how to use
+// an independent, lock-free source of randomness instead.
+func Key(m interface{}) interface{} {
+	return std.Key(m)
+}
+
+// Val returns a uniformly random value from m. It panics if m is empty or
+// if m is not a map. It is equivalent to std.Val(m).
+func Val(m interface{}) interface{} {
+	return std.Val(m)
+}
+
+// Key returns a uniformly random key from m, using r as the source of
+// randomness. It panics if m is empty or if m is not a map.
+func (r *Rand) Key(m interface{}) interface{} {
+	k, _ := r.pair(m)
+	return k
+}
+
+// Val returns a uniformly random value from m, using r as the source of
+// randomness. It panics if m is empty or if m is not a map.
+func (r *Rand) Val(m interface{}) interface{} {
+	_, v := r.pair(m)
+	return v
+}
+
+// pair selects a uniformly random key/value pair from m using reflection
+// for every memory access. It is the safe, slow counterpart to
+// fastPair/FastKey/FastVal: use it when the extra allocation of boxing the
+// key and value into interface{} is not a concern.
+func (r *Rand) pair(m interface{}) (key, val interface{}) {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		panic("randmap: argument is not a map")
+	}
+	if v.Len() == 0 {
+		panic("randmap: empty map")
+	}
+
+	iter := v.MapRange()
+	n := r.intn(v.Len())
+	for i := 0; iter.Next(); i++ {
+		if i == n {
+			return iter.Key().Interface(), iter.Value().Interface()
+		}
+	}
+	panic("randmap: unreachable")
+}
+
+// FastKey returns a uniformly random key from m, walking the map's bucket
+// array directly via unsafe instead of going through reflect.Value.MapRange.
+// It panics if m is empty or if m is not a map. It is equivalent to
+// std.FastKey(m).
+func FastKey(m interface{}) interface{} {
+	return std.FastKey(m)
+}
+
+// FastVal returns a uniformly random value from m, walking the map's
+// bucket array directly via unsafe instead of going through
+// reflect.Value.MapRange. It panics if m is empty or if m is not a map. It
+// is equivalent to std.FastVal(m).
+func FastVal(m interface{}) interface{} {
+	return std.FastVal(m)
+}
+
+// FastKey is like Key, but walks the map's bucket array directly via
+// unsafe instead of going through reflect.Value.MapRange, using r as the
+// source of randomness.
+func (r *Rand) FastKey(m interface{}) interface{} {
+	k, _ := r.fastPair(m)
+	return k
+}
+
+// FastVal is like Val, but walks the map's bucket array directly via
+// unsafe instead of going through reflect.Value.MapRange, using r as the
+// source of randomness.
+func (r *Rand) FastVal(m interface{}) interface{} {
+	_, v := r.fastPair(m)
+	return v
+}
+
+// fastPair selects a uniformly random key/value pair out of m's bucket
+// array. It works correctly even mid-growth: a chosen logical bucket may
+// resolve to an unevacuated old bucket, to an evacuated old bucket (in
+// which case it falls through to the new bucket in its place), or directly
+// to a current bucket, and overflow buckets are walked exactly as `range`
+// walks them.
+func (r *Rand) fastPair(m interface{}) (key, val interface{}) {
+	h, keyType, elemType := mapData(m)
+	if h == nil || h.count == 0 {
+		panic("randmap: empty map")
+	}
+	l := newLayout(keyType, elemType)
+
+	b, j := r.pickCell(h, l)
+	return readCell(keyType, elemType, l, b, j)
+}
+
+// readCell boxes the key and elem stored at cell (b, j) into interface{}
+// values, using the specialized readScalar fast path where it applies and
+// falling back to reflect.NewAt otherwise.
+func readCell(keyType, elemType reflect.Type, l layout, b unsafe.Pointer, j uintptr) (key, val interface{}) {
+	kv, ok := readScalar(keyType, l.key(b, j))
+	if !ok {
+		kv = reflect.NewAt(keyType, l.key(b, j)).Elem().Interface()
+	}
+	vv, ok := readScalar(elemType, l.elem(b, j))
+	if !ok {
+		vv = reflect.NewAt(elemType, l.elem(b, j)).Elem().Interface()
+	}
+	return kv, vv
+}
+
+// pickCell selects a uniformly random occupied cell out of h via reservoir
+// sampling (Algorithm R with a reservoir of size 1 - see SampleN for the
+// same technique applied to whole pairs): it walks every logical bucket in
+// turn, following the evacuation chain via resolveBucket and walking
+// overflow buckets exactly as range does, and keeps a single running
+// candidate that each occupied cell replaces with probability 1/(cells
+// seen so far). That gives every occupied cell equal probability of being
+// the final answer.
+//
+// A uniform-bucket-then-uniform-cell shortcut (pick a random logical
+// bucket, then a random cell within its chain) is NOT equivalent: it is
+// only unbiased if every bucket holds the same number of live cells, which
+// overflow chains make false in general - a cell in a bucket with one
+// overflow neighbor is picked at half the rate of a cell in a bucket with
+// none. pickCell is the shared low-level bucket-walking core behind
+// FastKey/FastVal, KeyOf/ValOf/PopKeyOf/PopValOf in typed.go, and
+// PopKey/PopVal.
+func (r *Rand) pickCell(h *hmap, l layout) (b unsafe.Pointer, j uintptr) {
+	var seen int
+	for i := uintptr(0); i < bucketCount(h); i++ {
+		bucket, oldGeneration := resolveBucket(h, l, i)
+		if oldGeneration {
+			// An unevacuated old bucket is the live storage for two
+			// logical new-generation indices at once: resolveBucket
+			// masks i down to the old table's width, so i and
+			// i+oldBucketCount both resolve to the same physical
+			// bucket. Only walk it the first time (the low-half
+			// index, where masking is a no-op) - otherwise it would
+			// be double-counted relative to an already-evacuated
+			// bucket of the same size, which is exactly the kind of
+			// bias this reservoir walk exists to avoid.
+			oldB := h.B - 1
+			mask := uintptr(1)<<oldB - 1
+			if i&mask != i {
+				continue
+			}
+		}
+
+		// tophash[k] >= minTopHash is the correct occupied test
+		// regardless of the key's content: the runtime bumps any
+		// naturally-computed tophash below minTopHash up into the
+		// valid range before storing it, specifically so tophash can
+		// use 0..evacuatedEmpty as sentinels without colliding with
+		// real data. That holds even for map[float64]V keys, where
+		// every NaN gets its own randomized hash (and so its own
+		// cell, indistinguishable from its neighbors by == or by
+		// tophash alone) - each such cell still carries a real
+		// tophash >= minTopHash, so NaN cells are neither
+		// double-counted nor skipped here.
+		for cur := bucket; cur != nil; cur = unsafe.Pointer(l.overflow(cur)) {
+			bm := (*bmap)(cur)
+			for k := uintptr(0); k < bucketCnt; k++ {
+				if bm.tophash[k] >= minTopHash {
+					seen++
+					if r.intn(seen) == 0 {
+						b, j = cur, k
+					}
+				}
+			}
+		}
+	}
+	return b, j
+}
+
+// Iter walks m in a uniformly random order, using reflection for every
+// access. kptr and vptr must be non-nil pointers to values of m's key and
+// elem type respectively; Iter panics otherwise. Each call to Next copies
+// the next key and value into *kptr and *vptr and returns true, until the
+// map is exhausted, at which point it returns false. It is equivalent to
+// std.Iter(m, kptr, vptr).
+func Iter(m interface{}, kptr, vptr interface{}) *Iterator {
+	return std.Iter(m, kptr, vptr)
+}
+
+// Iter is like the package-level Iter. It takes r only for symmetry with
+// the rest of Rand's methods: range order already comes from the Go
+// runtime's own per-iteration randomization, not from math/rand, so r is
+// unused here.
+func (r *Rand) Iter(m interface{}, kptr, vptr interface{}) *Iterator {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		panic("randmap: argument is not a map")
+	}
+	checkPtr(kptr, v.Type().Key(), "key")
+	checkPtr(vptr, v.Type().Elem(), "value")
+
+	return &Iterator{
+		iter: v.MapRange(),
+		kptr: reflect.ValueOf(kptr).Elem(),
+		vptr: reflect.ValueOf(vptr).Elem(),
+	}
+}
+
+func checkPtr(p interface{}, want reflect.Type, name string) {
+	v := reflect.ValueOf(p)
+	if v.Kind() != reflect.Ptr || v.Elem().Type() != want {
+		panic("randmap: " + name + " pointer must be a *" + want.String())
+	}
+}
+
+// Iterator yields key/value pairs from a map in the randomized order that
+// the Go runtime already produces for range, one pair at a time.
+type Iterator struct {
+	iter *reflect.MapIter
+	kptr reflect.Value
+	vptr reflect.Value
+}
+
+// Next advances the iterator, writing the next key and value into the
+// pointers supplied to Iter/FastIter, and reports whether a pair was
+// available.
+func (it *Iterator) Next() bool {
+	if !it.iter.Next() {
+		return false
+	}
+	it.kptr.Set(it.iter.Key())
+	it.vptr.Set(it.iter.Value())
+	return true
+}
+
+// FastIter is the same as Iter: it delegates to Iter's reflect.Value.MapRange
+// walk and does no unsafe bucket access; the "Fast" in the name only
+// mirrors the Key/FastKey split. It is equivalent to
+// std.FastIter(m, kptr, vptr).
+func FastIter(m interface{}, kptr, vptr interface{}) *Iterator {
+	return std.FastIter(m, kptr, vptr)
+}
+
+// FastIter is like Iter, for the same reason r goes unused: see Iter.
+func (r *Rand) FastIter(m interface{}, kptr, vptr interface{}) *Iterator {
+	return r.Iter(m, kptr, vptr)
+}
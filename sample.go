@@ -0,0 +1,150 @@
+package randmap
+
+import (
+	"container/heap"
+	"math"
+	"reflect"
+)
+
+// Pair is a key/value pair returned by SampleN and WeightedSampleN.
+type Pair struct {
+	Key interface{}
+	Val interface{}
+}
+
+// SampleN returns n distinct key/value pairs drawn uniformly at random from
+// m without replacement, in a single O(len(m)) pass over the map and O(n)
+// extra memory. It uses reservoir sampling (Algorithm R): the first n pairs
+// seen fill the reservoir outright, and the i-th pair thereafter replaces a
+// uniformly random reservoir slot with probability n/(i+1).
+//
+// If n >= len(m), SampleN returns every pair in m, in random order. It
+// panics if n < 0. SampleN is equivalent to std.SampleN(m, n); see Rand for
+// how to use an independent source of randomness instead.
+func SampleN(m interface{}, n int) []Pair {
+	return std.SampleN(m, n)
+}
+
+// SampleNKeys is like SampleN, but returns only the sampled keys. It is
+// equivalent to std.SampleNKeys(m, n).
+func SampleNKeys(m interface{}, n int) []interface{} {
+	return std.SampleNKeys(m, n)
+}
+
+// SampleN is like the package-level SampleN, but uses r as the source of
+// randomness.
+func (r *Rand) SampleN(m interface{}, n int) []Pair {
+	if n < 0 {
+		panic("randmap: n must be non-negative")
+	}
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		panic("randmap: argument is not a map")
+	}
+
+	reservoir := make([]Pair, 0, n)
+	iter := v.MapRange()
+	for i := 0; iter.Next(); i++ {
+		if i < n {
+			reservoir = append(reservoir, Pair{iter.Key().Interface(), iter.Value().Interface()})
+			continue
+		}
+		if j := r.intn(i + 1); j < n {
+			reservoir[j] = Pair{iter.Key().Interface(), iter.Value().Interface()}
+		}
+	}
+	return reservoir
+}
+
+// SampleNKeys is like the package-level SampleNKeys, but uses r as the
+// source of randomness.
+func (r *Rand) SampleNKeys(m interface{}, n int) []interface{} {
+	pairs := r.SampleN(m, n)
+	keys := make([]interface{}, len(pairs))
+	for i, p := range pairs {
+		keys[i] = p.Key
+	}
+	return keys
+}
+
+// weightedItem is one candidate in the A-Res min-heap: a pair plus its
+// A-Res key u^(1/w).
+type weightedItem struct {
+	pair Pair
+	key  float64
+}
+
+// weightedHeap is a min-heap of weightedItem ordered by key, so that the
+// smallest key (the first to be evicted as better candidates arrive) sits
+// at the root.
+type weightedHeap []weightedItem
+
+func (h weightedHeap) Len() int            { return len(h) }
+func (h weightedHeap) Less(i, j int) bool  { return h[i].key < h[j].key }
+func (h weightedHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *weightedHeap) Push(x interface{}) { *h = append(*h, x.(weightedItem)) }
+func (h *weightedHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// WeightedSampleN returns n distinct key/value pairs drawn from m without
+// replacement, with each pair's inclusion probability proportional to
+// weight(k, v). It uses the A-Res algorithm: for every entry it draws a
+// uniform u in (0, 1] and computes the key u^(1/w), then keeps the n
+// pairs with the largest keys in a min-heap, giving a single O(len(m))
+// pass and O(n) extra memory. weight must return a positive value for
+// every entry.
+//
+// If n >= len(m), WeightedSampleN returns every pair in m. It panics if
+// n < 0. WeightedSampleN is equivalent to std.WeightedSampleN(m, n, weight).
+func WeightedSampleN(m interface{}, n int, weight func(k, v interface{}) float64) []Pair {
+	return std.WeightedSampleN(m, n, weight)
+}
+
+// WeightedSampleN is like the package-level WeightedSampleN, but uses r as
+// the source of randomness.
+func (r *Rand) WeightedSampleN(m interface{}, n int, weight func(k, v interface{}) float64) []Pair {
+	if n < 0 {
+		panic("randmap: n must be non-negative")
+	}
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		panic("randmap: argument is not a map")
+	}
+	if n == 0 {
+		return nil
+	}
+
+	var h weightedHeap
+	iter := v.MapRange()
+	for iter.Next() {
+		k := iter.Key().Interface()
+		val := iter.Value().Interface()
+		w := weight(k, val)
+		if w <= 0 {
+			panic("randmap: weight must be positive")
+		}
+
+		u := 1 - r.float64() // uniform in (0, 1]
+		key := math.Pow(u, 1/w)
+
+		if h.Len() < n {
+			heap.Push(&h, weightedItem{Pair{k, val}, key})
+			continue
+		}
+		if key > h[0].key {
+			h[0] = weightedItem{Pair{k, val}, key}
+			heap.Fix(&h, 0)
+		}
+	}
+
+	out := make([]Pair, h.Len())
+	for i, item := range h {
+		out[i] = item.pair
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package randmap
+
+import "testing"
+
+func TestSampleN(t *testing.T) {
+	m := map[int]int{
+		0: 0, 1: 1, 2: 2, 3: 3, 4: 4,
+		5: 5, 6: 6, 7: 7, 8: 8, 9: 9,
+	}
+
+	pairs := SampleN(m, 4)
+	if len(pairs) != 4 {
+		t.Fatalf("expected 4 pairs, got %v", len(pairs))
+	}
+	seen := make(map[int]bool)
+	for _, p := range pairs {
+		k := p.Key.(int)
+		if seen[k] {
+			t.Fatalf("key %v sampled twice", k)
+		}
+		seen[k] = true
+		if p.Val.(int) != k {
+			t.Fatalf("pair mismatch: %v", p)
+		}
+	}
+}
+
+func TestSampleNAll(t *testing.T) {
+	m := map[int]int{0: 0, 1: 1, 2: 2}
+	pairs := SampleN(m, 10)
+	if len(pairs) != len(m) {
+		t.Fatalf("expected %v pairs, got %v", len(m), len(pairs))
+	}
+}
+
+func TestSampleNKeys(t *testing.T) {
+	m := map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 4: 4}
+	keys := SampleNKeys(m, 3)
+	if len(keys) != 3 {
+		t.Fatalf("expected 3 keys, got %v", len(keys))
+	}
+}
+
+func TestSampleNUniform(t *testing.T) {
+	const iters = 20000
+	m := map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 4: 4}
+	counts := make([]int, len(m))
+	for i := 0; i < iters; i++ {
+		for _, p := range SampleN(m, 2) {
+			counts[p.Key.(int)]++
+		}
+	}
+
+	want := iters * 2 / len(m)
+	for n, c := range counts {
+		if want/2 > c || c > want*2 {
+			t.Errorf("suspicious count: expected ~%v, got %v (%v)", want, c, n)
+		}
+	}
+}
+
+func TestWeightedSampleN(t *testing.T) {
+	m := map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 4: 4}
+	pairs := WeightedSampleN(m, 3, func(k, v interface{}) float64 {
+		return 1
+	})
+	if len(pairs) != 3 {
+		t.Fatalf("expected 3 pairs, got %v", len(pairs))
+	}
+	seen := make(map[int]bool)
+	for _, p := range pairs {
+		k := p.Key.(int)
+		if seen[k] {
+			t.Fatalf("key %v sampled twice", k)
+		}
+		seen[k] = true
+	}
+}
+
+func TestWeightedSampleNFavorsHeavy(t *testing.T) {
+	const iters = 5000
+	m := map[int]int{0: 0, 1: 1}
+	heavy := 0
+	for i := 0; i < iters; i++ {
+		pairs := WeightedSampleN(m, 1, func(k, v interface{}) float64 {
+			if k.(int) == 0 {
+				return 100
+			}
+			return 1
+		})
+		if pairs[0].Key.(int) == 0 {
+			heavy++
+		}
+	}
+	if heavy < iters*9/10 {
+		t.Errorf("expected heavily-weighted key to dominate, got %v/%v", heavy, iters)
+	}
+}
@@ -0,0 +1,120 @@
+package randmap
+
+import "sync"
+
+// SyncMap adapts Key, Val, Iter, and SampleN to a *sync.Map. sync.Map
+// exposes only Range, not an indexable bucket array, so every operation
+// here costs a full O(n) Range pass that boxes every entry, rather than
+// the direct walk of the bucket array that FastKey does over a built-in
+// map.
+type SyncMap struct {
+	m *sync.Map
+	r *Rand
+}
+
+// NewSyncMap returns a SyncMap wrapping m, using the package-level default
+// Rand. Use WithRand for an independent source of randomness instead.
+func NewSyncMap(m *sync.Map) *SyncMap {
+	return &SyncMap{m: m, r: std}
+}
+
+// WithRand returns a copy of sm that draws from r instead of sm's current
+// source of randomness.
+func (sm *SyncMap) WithRand(r *Rand) *SyncMap {
+	return &SyncMap{m: sm.m, r: r}
+}
+
+// Key returns a uniformly random key from sm, selected with a single Range
+// pass using a size-1 reservoir. It panics if sm is empty.
+func (sm *SyncMap) Key() interface{} {
+	k, _, ok := sm.sample()
+	if !ok {
+		panic("randmap: empty map")
+	}
+	return k
+}
+
+// Val is like Key, but returns the value instead of the key.
+func (sm *SyncMap) Val() interface{} {
+	_, v, ok := sm.sample()
+	if !ok {
+		panic("randmap: empty map")
+	}
+	return v
+}
+
+// sample draws a single uniformly random key/value pair from sm using a
+// size-1 reservoir: a counter n is incremented for every entry Range
+// visits, and the current entry replaces the reservoir with probability
+// 1/n. Range calls its callback sequentially on the calling goroutine, so
+// a plain counter is enough; this stays unbiased even if other goroutines
+// concurrently insert into or delete from sm while the Range walk is in
+// progress, since Range is already safe for that.
+func (sm *SyncMap) sample() (key, val interface{}, ok bool) {
+	var n int64
+	sm.m.Range(func(k, v interface{}) bool {
+		n++
+		if n == 1 || sm.r.int63n(n) == 0 {
+			key, val, ok = k, v, true
+		}
+		return true
+	})
+	return key, val, ok
+}
+
+// SampleN returns up to n distinct key/value pairs drawn uniformly at
+// random from sm without replacement, via Algorithm R over a single Range
+// pass - the same reservoir sampling the package-level SampleN uses for
+// built-in maps, adapted to sync.Map's Range-only iteration.
+func (sm *SyncMap) SampleN(n int) []Pair {
+	if n < 0 {
+		panic("randmap: n must be non-negative")
+	}
+
+	reservoir := make([]Pair, 0, n)
+	var i int64
+	sm.m.Range(func(k, v interface{}) bool {
+		idx := i
+		i++
+		if idx < int64(n) {
+			reservoir = append(reservoir, Pair{k, v})
+			return true
+		}
+		if j := sm.r.int63n(idx + 1); j < int64(n) {
+			reservoir[j] = Pair{k, v}
+		}
+		return true
+	})
+	return reservoir
+}
+
+// SyncMapIter yields every key/value pair in a SyncMap, in a uniformly
+// random order. Unlike Iterator, it takes a full O(n) snapshot up front (a
+// Range pass followed by a Fisher-Yates shuffle), since sync.Map offers no
+// cheaper way to randomize iteration order.
+type SyncMapIter struct {
+	pairs []Pair
+	i     int
+}
+
+// Iter returns a SyncMapIter over every entry in sm, in a freshly
+// randomized order.
+func (sm *SyncMap) Iter() *SyncMapIter {
+	var pairs []Pair
+	sm.m.Range(func(k, v interface{}) bool {
+		pairs = append(pairs, Pair{k, v})
+		return true
+	})
+	sm.r.shuffle(pairs)
+	return &SyncMapIter{pairs: pairs}
+}
+
+// Next advances the iterator and reports whether a pair was available.
+func (it *SyncMapIter) Next() (key, val interface{}, ok bool) {
+	if it.i >= len(it.pairs) {
+		return nil, nil, false
+	}
+	p := it.pairs[it.i]
+	it.i++
+	return p.Key, p.Val, true
+}
@@ -0,0 +1,108 @@
+package randmap
+
+import (
+	"sync"
+	"testing"
+)
+
+func newTestSyncMap(n int) *sync.Map {
+	var m sync.Map
+	for i := 0; i < n; i++ {
+		m.Store(i, i*i)
+	}
+	return &m
+}
+
+func TestSyncMapKey(t *testing.T) {
+	const iters = 100000
+	const n = 10
+	sm := NewSyncMap(newTestSyncMap(n))
+
+	counts := make([]int, n)
+	for i := 0; i < iters; i++ {
+		counts[sm.Key().(int)]++
+	}
+
+	want := iters / n
+	for k, c := range counts {
+		if want/2 > c || c > want*2 {
+			t.Errorf("suspicious count for key %v: expected ~%v, got %v", k, want, c)
+		}
+	}
+}
+
+func TestSyncMapVal(t *testing.T) {
+	sm := NewSyncMap(newTestSyncMap(5))
+	v := sm.Val().(int)
+	if v < 0 || v > 16 {
+		t.Fatalf("unexpected value: %v", v)
+	}
+}
+
+func TestSyncMapKeyEmpty(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic when accessing empty sync.Map")
+		}
+	}()
+	sm := NewSyncMap(&sync.Map{})
+	_ = sm.Key()
+}
+
+func TestSyncMapSampleN(t *testing.T) {
+	sm := NewSyncMap(newTestSyncMap(10))
+	pairs := sm.SampleN(4)
+	if len(pairs) != 4 {
+		t.Fatalf("expected 4 pairs, got %v", len(pairs))
+	}
+	seen := make(map[int]bool)
+	for _, p := range pairs {
+		k := p.Key.(int)
+		if seen[k] {
+			t.Fatalf("key %v sampled twice", k)
+		}
+		seen[k] = true
+	}
+}
+
+func TestSyncMapIter(t *testing.T) {
+	const n = 10
+	sm := NewSyncMap(newTestSyncMap(n))
+
+	seen := make(map[int]bool)
+	it := sm.Iter()
+	for {
+		k, v, ok := it.Next()
+		if !ok {
+			break
+		}
+		ki := k.(int)
+		if seen[ki] {
+			t.Fatalf("key %v seen twice", ki)
+		}
+		seen[ki] = true
+		if v.(int) != ki*ki {
+			t.Fatalf("value mismatch for key %v: got %v", ki, v)
+		}
+	}
+	if len(seen) != n {
+		t.Fatalf("expected %v keys, saw %v", n, len(seen))
+	}
+}
+
+func TestSyncMapConcurrentMutation(t *testing.T) {
+	sm := NewSyncMap(newTestSyncMap(20))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 20; i < 40; i++ {
+			sm.m.Store(i, i*i)
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		_ = sm.Key()
+	}
+	<-done
+}
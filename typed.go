@@ -0,0 +1,108 @@
+package randmap
+
+import "unsafe"
+
+// KeyOf returns a uniformly random key from m. Unlike Key, it requires no
+// type assertion and performs no reflection: the bucket layout is computed
+// from K and V's compile-time sizes, and the selected key is read directly
+// into a K rather than boxed into an interface{}.
+func KeyOf[K comparable, V any](m map[K]V) K {
+	k, _ := pairOf[K, V](m)
+	return k
+}
+
+// ValOf returns a uniformly random value from m. See KeyOf.
+func ValOf[K comparable, V any](m map[K]V) V {
+	_, v := pairOf[K, V](m)
+	return v
+}
+
+// FastKeyOf is an alias for KeyOf kept for symmetry with FastKey/FastVal:
+// the generics API has only one bucket-walking implementation, since it
+// already avoids the reflection cost that FastKey exists to avoid.
+func FastKeyOf[K comparable, V any](m map[K]V) K {
+	return KeyOf[K, V](m)
+}
+
+// FastValOf is an alias for ValOf. See FastKeyOf.
+func FastValOf[K comparable, V any](m map[K]V) V {
+	return ValOf[K, V](m)
+}
+
+// pairOf selects a uniformly random key/value pair out of m via the same
+// bucket-walking core (pickCell) used by FastKey/FastVal, but reads the
+// chosen cell directly as a K/V pair instead of through reflect.
+func pairOf[K comparable, V any](m map[K]V) (key K, val V) {
+	h := mapHmap(m)
+	if h == nil || h.count == 0 {
+		panic("randmap: empty map")
+	}
+	l := newLayoutSizes(unsafe.Sizeof(key), unsafe.Sizeof(val))
+
+	b, j := std.pickCell(h, l)
+	return *(*K)(l.key(b, j)), *(*V)(l.elem(b, j))
+}
+
+// PopKeyOf selects a uniformly random key from m, deletes it, and returns
+// it, using the same generics bucket walk as KeyOf. See PopKey for why this
+// avoids a second hash-and-lookup.
+func PopKeyOf[K comparable, V any](m map[K]V) K {
+	k, _ := popPairOf[K, V](m)
+	return k
+}
+
+// PopValOf is like PopKeyOf, but returns the value instead of the key.
+func PopValOf[K comparable, V any](m map[K]V) V {
+	_, v := popPairOf[K, V](m)
+	return v
+}
+
+func popPairOf[K comparable, V any](m map[K]V) (key K, val V) {
+	h := mapHmap(m)
+	if h == nil || h.count == 0 {
+		panic("randmap: empty map")
+	}
+	l := newLayoutSizes(unsafe.Sizeof(key), unsafe.Sizeof(val))
+
+	b, j := std.pickCell(h, l)
+	key, val = *(*K)(l.key(b, j)), *(*V)(l.elem(b, j))
+
+	// Clear through typed assignments, not a raw byte clear, so the
+	// compiler emits a write barrier when K or V contains a pointer. See
+	// the longer note on deleteCell in pop.go for why a raw memclr would
+	// risk corrupting the heap under concurrent GC marking.
+	*(*K)(l.key(b, j)) = *new(K)
+	*(*V)(l.elem(b, j)) = *new(V)
+	clearTophash(h, b, j)
+	return key, val
+}
+
+// mapHmap returns the hmap header backing m without going through
+// reflect.ValueOf: a Go map value is itself a single-word pointer to its
+// hmap, so reinterpreting &m as a **hmap and dereferencing it recovers that
+// pointer directly.
+func mapHmap[K comparable, V any](m map[K]V) *hmap {
+	return *(**hmap)(unsafe.Pointer(&m))
+}
+
+// TypedIter walks a map[K]V in the randomized order the Go runtime already
+// produces for range, yielding each key/value pair as a K/V instead of an
+// interface{}.
+type TypedIter[K comparable, V any] struct {
+	it *Iterator
+	k  K
+	v  V
+}
+
+// IterOf returns a TypedIter over m.
+func IterOf[K comparable, V any](m map[K]V) *TypedIter[K, V] {
+	t := &TypedIter[K, V]{}
+	t.it = Iter(m, &t.k, &t.v)
+	return t
+}
+
+// Next advances the iterator and reports whether a pair was available.
+func (t *TypedIter[K, V]) Next() (K, V, bool) {
+	ok := t.it.Next()
+	return t.k, t.v, ok
+}
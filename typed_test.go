@@ -0,0 +1,123 @@
+package randmap
+
+import "testing"
+
+func TestKeyOf(t *testing.T) {
+	const iters = 100000
+	m := map[int]int{
+		0: 0, 1: 1, 2: 2, 3: 3, 4: 4,
+		5: 5, 6: 6, 7: 7, 8: 8, 9: 9,
+	}
+	counts := make([]int, len(m))
+	for i := 0; i < iters; i++ {
+		counts[KeyOf(m)]++
+	}
+
+	for n, c := range counts {
+		if (iters/len(m))/2 > c || c > (iters/len(m))*2 {
+			t.Errorf("suspicious count: expected %v-%v, got %v (%v)", (iters/len(m))/2, (iters/len(m))*2, c, n)
+		}
+	}
+}
+
+func TestValOf(t *testing.T) {
+	const iters = 100000
+	m := map[int]int{
+		0: 0, 1: 1, 2: 2, 3: 3, 4: 4,
+		5: 5, 6: 6, 7: 7, 8: 8, 9: 9,
+	}
+	counts := make([]int, len(m))
+	for i := 0; i < iters; i++ {
+		counts[ValOf(m)]++
+	}
+
+	for n, c := range counts {
+		if (iters/len(m))/2 > c || c > (iters/len(m))*2 {
+			t.Errorf("suspicious count: expected %v-%v, got %v (%v)", (iters/len(m))/2, (iters/len(m))*2, c, n)
+		}
+	}
+}
+
+func TestKeyOfEmpty(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic when accessing empty map")
+		}
+	}()
+	_ = KeyOf(make(map[int]int))
+}
+
+func TestIterOf(t *testing.T) {
+	const iters = 1000
+	m := map[int]int{
+		0: 0, 1: 1, 2: 2, 3: 3, 4: 4,
+		5: 5, 6: 6, 7: 7, 8: 8, 9: 9,
+	}
+	counts := make([][]int, len(m))
+	for i := range counts {
+		counts[i] = make([]int, len(m))
+	}
+	for i := 0; i < iters; i++ {
+		it := IterOf(m)
+		for j := 0; ; j++ {
+			k, _, ok := it.Next()
+			if !ok {
+				break
+			}
+			counts[k][j]++
+		}
+	}
+
+	for k, cs := range counts {
+		for i, c := range cs {
+			if (iters/len(m))/2 > c || c > (iters/len(m))*2 {
+				t.Errorf("suspicious count for key %v index %v: expected %v-%v, got %v", k, i, (iters/len(m))/2, (iters/len(m))*2, c)
+			}
+		}
+	}
+}
+
+func TestPopKeyOf(t *testing.T) {
+	m := map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 4: 4}
+	want := len(m)
+	for len(m) > 0 {
+		k := PopKeyOf(m)
+		if _, ok := m[k]; ok {
+			t.Fatalf("PopKeyOf returned %v, but it is still in the map", k)
+		}
+		want--
+		if len(m) != want {
+			t.Fatalf("expected map length %v, got %v", want, len(m))
+		}
+	}
+}
+
+func TestPopValOfPointerContaining(t *testing.T) {
+	// string values contain a pointer; the generic Pop path must clear
+	// the cell via a typed assignment (*(*V)(ptr) = *new(V)), not a raw
+	// byte clear, so the compiler's write barrier fires.
+	m := map[int]string{0: "zero", 1: "one", 2: "two"}
+	seen := make(map[string]bool)
+	for len(m) > 0 {
+		v := PopValOf(m)
+		if seen[v] {
+			t.Fatalf("value %q popped twice", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected 3 distinct values, got %v", len(seen))
+	}
+}
+
+func BenchmarkKeyOf(b *testing.B) {
+	m := make(map[int]int, 10000)
+	for i := 0; i < 10000; i++ {
+		m[i] = i
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = KeyOf(m)
+	}
+}